@@ -1,9 +1,13 @@
 package rgbmatrix
 
 import (
+	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
+	"image/png"
+	"math"
+	"os"
 )
 
 // Canvas is a image.Image representation of a WS281x matrix, it implements
@@ -12,6 +16,15 @@ type Canvas struct {
 	w, h   int
 	m      Matrix
 	closed bool
+
+	// frame holds the pixels set since the last Render, and rendered the
+	// pixels of the last rendered frame, as shown by the matrix
+	frame    *image.RGBA
+	rendered *image.RGBA
+
+	gamma float64
+	scale float64
+	lut   *[256]uint8
 }
 
 // NewCanvas returns a new Canvas using the given width and height and creates
@@ -19,15 +32,46 @@ type Canvas struct {
 func NewCanvas(m Matrix) *Canvas {
 	w, h := m.Geometry()
 	return &Canvas{
-		w: w,
-		h: h,
-		m: m,
+		w:     w,
+		h:     h,
+		m:     m,
+		gamma: 1,
+		scale: 1,
 	}
 }
 
-// Render update the display with the data from the LED buffer
+// Render update the display with the data from the LED buffer, the buffer is
+// cleared afterwards, as the matrix does with its own buffer
 func (c *Canvas) Render() error {
-	return c.m.Render()
+	if c.lut != nil {
+		c.correct()
+	}
+
+	if err := c.m.Render(); err != nil {
+		return err
+	}
+
+	c.flush()
+	return nil
+}
+
+// flush copies the pending frame to the rendered one, corrected and opaque as
+// the leds show it, and clears the pending frame
+func (c *Canvas) flush() {
+	frame, rendered := c.shadow(), c.last()
+	for i := 0; i < len(frame.Pix); i += 4 {
+		r, g, b := frame.Pix[i], frame.Pix[i+1], frame.Pix[i+2]
+		if c.lut != nil {
+			r, g, b = c.lut[r], c.lut[g], c.lut[b]
+		}
+
+		rendered.Pix[i], rendered.Pix[i+1], rendered.Pix[i+2] = r, g, b
+		rendered.Pix[i+3] = 0xff
+	}
+
+	for i := range frame.Pix {
+		frame.Pix[i] = 0
+	}
 }
 
 // ColorModel returns the canvas' color model, always color.RGBAModel
@@ -40,28 +84,167 @@ func (c *Canvas) Bounds() image.Rectangle {
 	return image.Rect(0, 0, c.w, c.h)
 }
 
-// At returns the color of the pixel at (x, y)
+// At returns the color of the pixel at (x, y) as set since the last Render,
+// pixels not set and coordinates outside of the canvas return a transparent
+// color
 func (c *Canvas) At(x, y int) color.Color {
-	return c.m.At(c.position(x, y))
+	return c.shadow().At(x, y)
 }
 
 // Set set LED at position x,y to the provided 24-bit color value
 func (c *Canvas) Set(x, y int, color color.Color) {
+	c.shadow().Set(x, y, color)
 	c.m.Set(c.position(x, y), color)
 }
 
+// RGBA64At returns the color of the pixel at (x, y) as At does, implementing
+// image.RGBA64Image allows draw.Draw to read the canvas without allocations
+func (c *Canvas) RGBA64At(x, y int) color.RGBA64 {
+	return c.shadow().RGBA64At(x, y)
+}
+
+// SetRGBA64 set LED at position x,y to the provided color as Set does,
+// implementing draw.RGBA64Image allows draw.Draw to write the canvas without
+// allocating a color per pixel
+func (c *Canvas) SetRGBA64(x, y int, clr color.RGBA64) {
+	c.shadow().SetRGBA64(x, y, clr)
+	c.m.Set(c.position(x, y), clr)
+}
+
+// SetPixel set LED at position x,y to the provided color, coordinates outside
+// of the canvas are ignored. The change is not visible until Render is called
+func (c *Canvas) SetPixel(x, y int, color color.Color) {
+	if !(image.Point{x, y}).In(c.Bounds()) {
+		return
+	}
+
+	c.Set(x, y, color)
+}
+
+// FillRect set all the LEDs inside of r to the provided color, the part of r
+// outside of the canvas is ignored. The change is not visible until Render is
+// called
+func (c *Canvas) FillRect(r image.Rectangle, color color.Color) {
+	r = r.Intersect(c.Bounds())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c.Set(x, y, color)
+		}
+	}
+}
+
+// SetGamma sets the gamma correction applied to every pixel sent to the
+// matrix, 1.0 by default meaning no correction. Values greater than 1 darken
+// the mid-tones, matching better the perceived brightness of the leds. The
+// correction is applied on Render, to every pixel set since the last Render.
+func (c *Canvas) SetGamma(g float64) error {
+	if g <= 0 {
+		return fmt.Errorf("invalid gamma %v, must be greater than 0", g)
+	}
+
+	c.gamma = g
+	c.updateLUT()
+	return nil
+}
+
+// SetBrightnessScale scales the channels of every pixel sent to the matrix,
+// 1.0 by default. Unlike the hardware brightness, this allows a smooth
+// dimming. The scale is applied on Render, to every pixel set since the last
+// Render.
+func (c *Canvas) SetBrightnessScale(s float64) error {
+	if s < 0 {
+		return fmt.Errorf("invalid brightness scale %v, must be positive", s)
+	}
+
+	c.scale = s
+	c.updateLUT()
+	return nil
+}
+
+// updateLUT precomputes the gamma and brightness correction of every channel
+// value, the lookup table is nil when no correction is needed.
+func (c *Canvas) updateLUT() {
+	if c.gamma == 1 && c.scale == 1 {
+		c.lut = nil
+		return
+	}
+
+	c.lut = &[256]uint8{}
+	for i := range c.lut {
+		v := math.Pow(float64(i)/255, c.gamma) * c.scale * 255
+		c.lut[i] = uint8(math.Min(math.Floor(v+0.5), 255))
+	}
+}
+
+// correct sends to the matrix every pixel of the pending frame corrected with
+// the lookup table, overriding the values sent by Set
+func (c *Canvas) correct() {
+	frame := c.shadow()
+	for i := 0; i < len(frame.Pix); i += 4 {
+		r, g, b := frame.Pix[i], frame.Pix[i+1], frame.Pix[i+2]
+		c.m.Set(i/4, color.RGBA{c.lut[r], c.lut[g], c.lut[b], 0xff})
+	}
+}
+
+// SavePNG writes the last rendered frame to the given path as a PNG file,
+// pixels set after the last Render are not included
+func (c *Canvas) SavePNG(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := png.Encode(f, c.last()); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// shadow returns an in-memory copy of the pixels written to the matrix since
+// the last Render, since the matrix buffers can't be read back
+func (c *Canvas) shadow() *image.RGBA {
+	if c.frame == nil {
+		c.frame = image.NewRGBA(c.Bounds())
+	}
+
+	return c.frame
+}
+
+// last returns the last rendered frame, black if nothing was rendered yet
+func (c *Canvas) last() *image.RGBA {
+	if c.rendered == nil {
+		c.rendered = image.NewRGBA(c.Bounds())
+		draw.Draw(c.rendered, c.rendered.Bounds(), image.Black, image.ZP, draw.Src)
+	}
+
+	return c.rendered
+}
+
 func (c *Canvas) position(x, y int) int {
 	return x + (y * c.w)
 }
 
 // Clear set all the leds on the matrix with color.Black
 func (c *Canvas) Clear() error {
-	draw.Draw(c, c.Bounds(), &image.Uniform{color.Black}, image.ZP, draw.Src)
-	return c.m.Render()
+	for y := 0; y < c.h; y++ {
+		for x := 0; x < c.w; x++ {
+			c.Set(x, y, color.Black)
+		}
+	}
+
+	return c.Render()
 }
 
-// Close clears the matrix and close the matrix
+// Close clears the matrix and close the matrix, calling Close more than once
+// has no effect
 func (c *Canvas) Close() error {
+	if c.closed {
+		return nil
+	}
+
+	c.closed = true
 	c.Clear()
 	return c.m.Close()
 }
@@ -73,5 +256,6 @@ type Matrix interface {
 	Set(position int, c color.Color)
 	Apply([]color.Color) error
 	Render() error
+	SetBrightness(brightness int) error
 	Close() error
 }
@@ -4,11 +4,14 @@ import (
 	"fmt"
 	"image"
 	"image/color"
+	"math"
 	"os"
+	"strings"
 	"sync"
 
 	"golang.org/x/exp/shiny/driver"
 	"golang.org/x/exp/shiny/screen"
+	"golang.org/x/mobile/event/key"
 	"golang.org/x/mobile/event/paint"
 	"golang.org/x/mobile/event/size"
 )
@@ -16,6 +19,16 @@ import (
 const DefaultPixelPitch = 12
 const windowTitle = "RGB led matrix emulator"
 
+// LEDShape is the shape used to draw the leds
+type LEDShape int
+
+const (
+	// Square leds fill all the space between the gutters, the default
+	Square LEDShape = iota
+	// Circle leds look closer to the ones of a real matrix
+	Circle
+)
+
 type Emulator struct {
 	PixelPitch              int
 	Gutter                  int
@@ -24,12 +37,25 @@ type Emulator struct {
 	GutterColor             color.Color
 	PixelPitchToGutterRatio int
 	Margin                  int
+	// Brightness of the emulated leds in percent, valid range is 1..100, 0
+	// means full brightness
+	Brightness int
+	// Shape of the emulated leds, Square by default
+	Shape LEDShape
 
 	leds []color.Color
 	w    screen.Window
 	s    screen.Screen
 	wg   sync.WaitGroup
 
+	// scale is the factor given to SetScale, the size of the leds and the
+	// gutter at scale 1 are derived from it
+	scale int
+
+	mu    sync.Mutex
+	frame *image.RGBA
+	keys  []func(key string)
+
 	isReady bool
 }
 
@@ -40,6 +66,7 @@ func NewEmulator(w, h, pixelPitch int, autoInit bool) *Emulator {
 		GutterColor:             color.Gray{Y: 20},
 		PixelPitchToGutterRatio: 2,
 		Margin:                  10,
+		Brightness:              100,
 	}
 	e.updatePixelPitchForGutter(pixelPitch / e.PixelPitchToGutterRatio)
 
@@ -92,6 +119,8 @@ func (e *Emulator) mainWindowLoop(s screen.Screen) {
 			e.isReady = true
 		case size.Event:
 			sz = evn
+		case key.Event:
+			e.handleKey(evn)
 
 		case error:
 			fmt.Fprintln(os.Stderr, e)
@@ -159,6 +188,70 @@ func (e *Emulator) calculateGutterForViewableArea(size image.Point) int {
 	return maxGutterInY
 }
 
+// fillLED draws a led in the given Rectangle, circles are drawn as a fill per
+// row of pixels since the window can only fill rectangles.
+func (e *Emulator) fillLED(r image.Rectangle, c color.Color) {
+	if e.Shape != Circle {
+		e.w.Fill(r, c, screen.Over)
+		return
+	}
+
+	radius := float64(r.Dx()) / 2
+	center := r.Min.X + r.Dx()/2
+	for y := 0; y < r.Dy(); y++ {
+		dy := float64(y) + 0.5 - radius
+		dx := int(math.Sqrt(radius*radius-dy*dy) + 0.5)
+		e.w.Fill(image.Rect(center-dx, r.Min.Y+y, center+dx, r.Min.Y+y+1), c, screen.Over)
+	}
+}
+
+// SetScale sets the size of the leds and the gutter to factor times their size
+// at scale 1, and with them the initial size of the window, factor must be 1
+// or greater. Must be called before Init.
+func (e *Emulator) SetScale(factor int) error {
+	if factor < 1 {
+		return fmt.Errorf("invalid scale %d, must be 1 or greater", factor)
+	}
+
+	if e.scale < 1 {
+		e.scale = 1
+	}
+
+	e.updateGutterForPixelPitch(e.PixelPitch / e.scale * factor)
+	e.scale = factor
+	return nil
+}
+
+// SetPixelPitchToGutterRatio sets how many times the leds are wider than the
+// gutter between them, keeping the size of the leds. The ratio must be 1 or
+// greater, the gutter is rounded down but kept at least one pixel wide. Must
+// be called before Init.
+func (e *Emulator) SetPixelPitchToGutterRatio(ratio int) error {
+	if ratio < 1 {
+		return fmt.Errorf("invalid pixel pitch to gutter ratio %d, must be 1 or greater", ratio)
+	}
+
+	e.PixelPitchToGutterRatio = ratio
+	e.updateGutterForPixelPitch(e.PixelPitch)
+	return nil
+}
+
+// DisplaySize returns the size of the emulated matrix on screen, including
+// the margins
+func (e *Emulator) DisplaySize() image.Point {
+	return e.matrixWithMarginsRect().Size()
+}
+
+// updateGutterForPixelPitch sets the pixel pitch and the gutter following
+// PixelPitchToGutterRatio, at least one pixel wide
+func (e *Emulator) updateGutterForPixelPitch(pitch int) {
+	e.PixelPitch = pitch
+	e.Gutter = pitch / e.PixelPitchToGutterRatio
+	if e.Gutter < 1 {
+		e.Gutter = 1
+	}
+}
+
 func (e *Emulator) updatePixelPitchForGutter(gutterWidth int) {
 	e.PixelPitch = e.PixelPitchToGutterRatio * gutterWidth
 	e.Gutter = gutterWidth
@@ -171,11 +264,19 @@ func (e *Emulator) Geometry() (width, height int) {
 func (e *Emulator) Apply(leds []color.Color) error {
 	defer func() { e.leds = make([]color.Color, e.Height*e.Width) }()
 
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.frame == nil {
+		e.frame = image.NewRGBA(image.Rect(0, 0, e.Width, e.Height))
+	}
+
 	var c color.Color
 	for col := 0; col < e.Width; col++ {
 		for row := 0; row < e.Height; row++ {
-			c = e.At(col + (row * e.Width))
-			e.w.Fill(e.ledRect(col, row), c, screen.Over)
+			c = e.dim(e.At(col + (row * e.Width)))
+			e.fillLED(e.ledRect(col, row), c)
+			e.frame.Set(col, row, c)
 		}
 	}
 
@@ -183,6 +284,51 @@ func (e *Emulator) Apply(leds []color.Color) error {
 	return nil
 }
 
+// Screenshot returns a copy of the frame being displayed by the emulator, one
+// pixel per led. Only available on the emulator, a real matrix can't be read
+// back.
+func (e *Emulator) Screenshot() image.Image {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	img := image.NewRGBA(image.Rect(0, 0, e.Width, e.Height))
+	if e.frame != nil {
+		copy(img.Pix, e.frame.Pix)
+	}
+
+	return img
+}
+
+// OnKey registers a function called every time a key is pressed on the
+// emulator window. Printable keys are reported as the typed character, other
+// keys by name, such as "UpArrow", "ReturnEnter" or "Escape". Only available
+// on the emulator, since a real matrix has no keyboard.
+func (e *Emulator) OnKey(f func(key string)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.keys = append(e.keys, f)
+}
+
+func (e *Emulator) handleKey(ev key.Event) {
+	if ev.Direction != key.DirPress {
+		return
+	}
+
+	name := strings.TrimPrefix(ev.Code.String(), "Code")
+	if ev.Rune > ' ' {
+		name = string(ev.Rune)
+	}
+
+	e.mu.Lock()
+	keys := e.keys
+	e.mu.Unlock()
+
+	for _, f := range keys {
+		f(name)
+	}
+}
+
 func (e *Emulator) Render() error {
 	return e.Apply(e.leds)
 }
@@ -199,6 +345,31 @@ func (e *Emulator) Set(position int, c color.Color) {
 	e.leds[position] = color.RGBAModel.Convert(c)
 }
 
+// SetBrightness changes the brightness of the emulated leds in percent, valid
+// range is 1..100, as on the real panels where 0 is not supported. The new
+// value is visible on the next Render
+func (e *Emulator) SetBrightness(brightness int) error {
+	if brightness < 1 || brightness > 100 {
+		return fmt.Errorf("invalid brightness %d, valid range is 1..100", brightness)
+	}
+
+	e.Brightness = brightness
+	return nil
+}
+
+func (e *Emulator) dim(c color.Color) color.Color {
+	if e.Brightness <= 0 || e.Brightness >= 100 {
+		return c
+	}
+
+	r, g, b, a := c.RGBA()
+	scale := func(v uint32) uint8 {
+		return uint8(v * uint32(e.Brightness) / 100 >> 8)
+	}
+
+	return color.RGBA{scale(r), scale(g), scale(b), uint8(a >> 8)}
+}
+
 func (e *Emulator) Close() error {
 	return nil
 }
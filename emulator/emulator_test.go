@@ -0,0 +1,114 @@
+package emulator
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/mobile/event/key"
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type EmulatorSuite struct{}
+
+var _ = Suite(&EmulatorSuite{})
+
+func (s *EmulatorSuite) TestNewEmulator(c *C) {
+	e := NewEmulator(64, 32, DefaultPixelPitch, false)
+	c.Assert(e.PixelPitch, Equals, 12)
+	c.Assert(e.Gutter, Equals, 6)
+	c.Assert(e.Shape, Equals, Square)
+	c.Assert(e.DisplaySize(), Equals, image.Pt(63*18+12+20, 31*18+12+20))
+}
+
+func (s *EmulatorSuite) TestSetScale(c *C) {
+	e := NewEmulator(64, 32, DefaultPixelPitch, false)
+	size := e.DisplaySize()
+
+	c.Assert(e.SetScale(2), IsNil)
+	c.Assert(e.PixelPitch, Equals, 24)
+	c.Assert(e.Gutter, Equals, 12)
+
+	margins := image.Pt(2*e.Margin, 2*e.Margin)
+	c.Assert(e.DisplaySize().Sub(margins), Equals, size.Sub(margins).Mul(2))
+
+	c.Assert(e.SetScale(0), ErrorMatches, "invalid scale 0, must be 1 or greater")
+	c.Assert(e.PixelPitch, Equals, 24)
+
+	// the factor is relative to the initial size, not cumulative
+	c.Assert(e.SetScale(2), IsNil)
+	c.Assert(e.PixelPitch, Equals, 24)
+	c.Assert(e.SetScale(1), IsNil)
+	c.Assert(e.PixelPitch, Equals, 12)
+	c.Assert(e.DisplaySize(), Equals, size)
+}
+
+func (s *EmulatorSuite) TestSetPixelPitchToGutterRatio(c *C) {
+	e := NewEmulator(64, 32, DefaultPixelPitch, false)
+	c.Assert(e.SetPixelPitchToGutterRatio(4), IsNil)
+
+	c.Assert(e.PixelPitch, Equals, 12)
+	c.Assert(e.Gutter, Equals, 3)
+
+	// the size of the leds is kept when the ratio doesn't divide it
+	for _, t := range []struct{ ratio, gutter int }{{5, 2}, {7, 1}, {13, 1}} {
+		c.Assert(e.SetPixelPitchToGutterRatio(t.ratio), IsNil)
+		c.Assert(e.PixelPitch, Equals, 12)
+		c.Assert(e.Gutter, Equals, t.gutter, Commentf("ratio %d", t.ratio))
+	}
+
+	c.Assert(e.SetPixelPitchToGutterRatio(0), ErrorMatches, "invalid pixel pitch to gutter ratio 0, must be 1 or greater")
+	c.Assert(e.PixelPitchToGutterRatio, Equals, 13)
+}
+
+func (s *EmulatorSuite) TestOnKey(c *C) {
+	e := NewEmulator(64, 32, DefaultPixelPitch, false)
+
+	var keys []string
+	e.OnKey(func(key string) { keys = append(keys, key) })
+
+	e.handleKey(key.Event{Rune: 'a', Direction: key.DirPress})
+	e.handleKey(key.Event{Rune: 'a', Direction: key.DirRelease})
+	e.handleKey(key.Event{Rune: -1, Code: key.CodeUpArrow, Direction: key.DirPress})
+	e.handleKey(key.Event{Rune: ' ', Code: key.CodeSpacebar, Direction: key.DirPress})
+
+	c.Assert(keys, DeepEquals, []string{"a", "UpArrow", "Spacebar"})
+}
+
+func (s *EmulatorSuite) TestScreenshot(c *C) {
+	e := NewEmulator(64, 32, DefaultPixelPitch, false)
+
+	img := e.Screenshot()
+	c.Assert(img.Bounds(), Equals, image.Rect(0, 0, 64, 32))
+	c.Assert(img.At(0, 0), Equals, color.RGBA{})
+}
+
+func (s *EmulatorSuite) TestSetBrightness(c *C) {
+	e := NewEmulator(64, 32, DefaultPixelPitch, false)
+	c.Assert(e.Brightness, Equals, 100)
+
+	c.Assert(e.SetBrightness(50), IsNil)
+	c.Assert(e.Brightness, Equals, 50)
+
+	for _, b := range []int{-1, 0, 101} {
+		c.Assert(e.SetBrightness(b), ErrorMatches, "invalid brightness .*, valid range is 1..100")
+	}
+
+	c.Assert(e.Brightness, Equals, 50)
+}
+
+func (s *EmulatorSuite) TestDim(c *C) {
+	e := NewEmulator(64, 32, DefaultPixelPitch, false)
+	c.Assert(e.dim(color.White), Equals, color.White)
+
+	e.SetBrightness(50)
+	c.Assert(e.dim(color.White), Equals, color.RGBA{127, 127, 127, 255})
+	c.Assert(e.dim(color.RGBA{200, 100, 0, 255}), Equals, color.RGBA{100, 50, 0, 255})
+}
+
+func (s *EmulatorSuite) TestDimZeroValue(c *C) {
+	e := &Emulator{}
+	c.Assert(e.dim(color.White), Equals, color.White)
+}
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"image"
 	"image/color"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/fogleman/gg"
@@ -15,7 +19,7 @@ var (
 	cols                     = flag.Int("led-cols", 32, "number of columns supported")
 	parallel                 = flag.Int("led-parallel", 1, "number of daisy-chained panels")
 	chain                    = flag.Int("led-chain", 2, "number of displays daisy-chained")
-	brightness               = flag.Int("brightness", 100, "brightness (0-100)")
+	brightness               = flag.Int("brightness", 100, "brightness (1-100)")
 	hardware_mapping         = flag.String("led-gpio-mapping", "regular", "Name of GPIO mapping used.")
 	show_refresh             = flag.Bool("led-show-refresh", false, "Show refresh rate.")
 	inverse_colors           = flag.Bool("led-inverse", false, "Switch if your matrix has inverse colors on.")
@@ -40,7 +44,17 @@ func main() {
 	tk := rgbmatrix.NewToolKit(m)
 	defer tk.Close()
 
-	tk.PlayAnimation(NewAnimation(image.Point{64, 32}))
+	// the leds remain on if the matrix is not closed, so the animation is
+	// stopped on Ctrl-C or SIGTERM letting the deferred Close clear the panel
+	ctx, cancel := context.WithCancel(context.Background())
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	tk.PlayAnimation(ctx, NewAnimation(image.Point{64, 32}))
 }
 
 func init() {
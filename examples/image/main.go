@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
 	"time"
@@ -14,7 +15,7 @@ var (
 	cols                     = flag.Int("led-cols", 32, "number of columns supported")
 	parallel                 = flag.Int("led-parallel", 1, "number of daisy-chained panels")
 	chain                    = flag.Int("led-chain", 2, "number of displays daisy-chained")
-	brightness               = flag.Int("brightness", 100, "brightness (0-100)")
+	brightness               = flag.Int("brightness", 100, "brightness (1-100)")
 	hardware_mapping         = flag.String("led-gpio-mapping", "regular", "Name of GPIO mapping used.")
 	show_refresh             = flag.Bool("led-show-refresh", false, "Show refresh rate.")
 	inverse_colors           = flag.Bool("led-inverse", false, "Switch if your matrix has inverse colors on.")
@@ -54,11 +55,13 @@ func main() {
 		tk.Transform = imaging.Rotate270
 	}
 
-	close, err := tk.PlayGIF(f)
-	fatal(err)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
 
-	time.Sleep(time.Second * 30)
-	close <- true
+	err = tk.PlayGIF(ctx, f)
+	if err != context.DeadlineExceeded {
+		fatal(err)
+	}
 }
 
 func init() {
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
 	"time"
@@ -20,12 +21,14 @@ func main() {
 	m, err := rpc.NewClient("tcp", "10.20.20.20:1234")
 	fatal(err)
 
-	tk := rgbmatrix.NewToolKit(m)
-	close, err := tk.PlayGIF(f)
-	fatal(err)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
 
-	time.Sleep(time.Second * 3)
-	close <- true
+	tk := rgbmatrix.NewToolKit(m)
+	err = tk.PlayGIF(ctx, f)
+	if err != context.DeadlineExceeded {
+		fatal(err)
+	}
 }
 
 func init() {
@@ -5,8 +5,8 @@ package rgbmatrix
 #cgo LDFLAGS: -lrgbmatrix -L${SRCDIR}/vendor/rpi-rgb-led-matrix/lib -lstdc++ -lm
 #include <led-matrix-c.h>
 
-void led_matrix_swap(struct RGBLedMatrix *matrix, struct LedCanvas *offscreen_canvas,
-                     int width, int height, const uint32_t pixels[]) {
+struct LedCanvas *led_matrix_swap(struct RGBLedMatrix *matrix, struct LedCanvas *offscreen_canvas,
+                                  int width, int height, const uint32_t pixels[]) {
 
 
   int i, x, y;
@@ -21,7 +21,9 @@ void led_matrix_swap(struct RGBLedMatrix *matrix, struct LedCanvas *offscreen_ca
     }
   }
 
-  offscreen_canvas = led_matrix_swap_on_vsync(matrix, offscreen_canvas);
+  // the canvas previously on display is returned, to be used as the next
+  // offscreen canvas
+  return led_matrix_swap_on_vsync(matrix, offscreen_canvas);
 }
 
 void set_show_refresh_rate(struct RGBLedMatrixOptions *o, int show_refresh_rate) {
@@ -38,9 +40,12 @@ void set_inverse_colors(struct RGBLedMatrixOptions *o, int inverse_colors) {
 */
 import "C"
 import (
+	"errors"
 	"fmt"
 	"image/color"
 	"os"
+	"strconv"
+	"strings"
 	"unsafe"
 
 	"github.com/mcuadros/go-rpi-rgb-led-matrix/emulator"
@@ -54,18 +59,26 @@ var DefaultConfig = HardwareConfig{
 	Parallel:          1,
 	PWMBits:           11,
 	PWMLSBNanoseconds: 130,
+	GPIOSlowdown:      1,
 	Brightness:        100,
 	ScanMode:          Progressive,
+	RGBSequence:       "RGB",
+	HardwareMapping:   "regular",
 }
 
 // HardwareConfig rgb-led-matrix configuration
+//
+// Rows and Cols describe a single panel, the size of the whole matrix as
+// returned by Geometry is Cols * ChainLength wide and Rows * Parallel high, so
+// three 64x64 panels in one chain give a 192x64 matrix.
 type HardwareConfig struct {
-	// Rows the number of rows supported by the display, so 32 or 16.
+	// Rows the number of rows supported by a single display, so 32 or 16.
 	Rows int
-	// Cols the number of columns supported by the display, so 32 or 64 .
+	// Cols the number of columns supported by a single display, so 32 or 64.
 	Cols int
-	// ChainLengthis the number of displays daisy-chained together
-	// (output of one connected to input of next).
+	// ChainLength is the number of displays daisy-chained together
+	// (output of one connected to input of next). The effective number of
+	// pixels in horizontal direction is then thus cols * chain length.
 	ChainLength int
 	// Parallel is the number of parallel chains connected to the Pi; in old Pis
 	// with 26 GPIO pins, that is 1, in newer Pis with 40 interfaces pins, that
@@ -74,17 +87,30 @@ type HardwareConfig struct {
 	Parallel int
 	// Set PWM bits used for output. Default is 11, but if you only deal with
 	// limited comic-colors, 1 might be sufficient. Lower require less CPU and
-	// increases refresh-rate.
+	// increases refresh-rate. Valid range is 1..11, 0 uses the library
+	// default.
 	PWMBits int
 	// Change the base time-unit for the on-time in the lowest significant bit in
 	// nanoseconds.  Higher numbers provide better quality (more accurate color,
 	// less ghosting), but have a negative impact on the frame rate.
 	PWMLSBNanoseconds int // the DMA channel to use
+	// GPIOSlowdown slows down the writes to the GPIO, needed for faster Pis or
+	// slower panels. Default is 1, valid range is 1..4, 0 uses the library
+	// default.
+	GPIOSlowdown int
 	// Brightness is the initial brightness of the panel in percent. Valid range
 	// is 1..100
 	Brightness int
 	// ScanMode progressive or interlaced
 	ScanMode ScanMode // strip color layout
+	// RowAddressType is how the rows are addressed by the panel: 0 direct
+	// (default), 1 AB-addressed, 2 direct row select, 3 ABC-addressed and 4
+	// ABC shift with DE direct.
+	RowAddressType int
+	// Multiplexing is the multiplexing scheme of outdoor panels: 0 direct
+	// (default), 1 stripe, 2 checkered, 3 spiral, 4 Z-stripe; check the library
+	// documentation for the full list.
+	Multiplexing int
 	// Disable the PWM hardware subsystem to create pulses. Typically, you don't
 	// want to disable hardware pulsing, this is mostly for debugging and figuring
 	// out if there is interference with the sound system.
@@ -92,15 +118,193 @@ type HardwareConfig struct {
 	// non-standard wirings.
 	DisableHardwarePulsing bool
 
+	// ShowRefreshRate makes the library print the achieved refresh rate of the
+	// panel on stderr, useful while tuning PWMBits and the GPIO slowdown. The C
+	// API doesn't report this rate, so it can't be read back from Go.
 	ShowRefreshRate bool
-	InverseColors   bool
-
-	// Name of GPIO mapping used
+	// InverseColors switch if your matrix has inverse colors on.
+	InverseColors bool
+
+	// LimitRefreshRateHz caps the rate at which the hardware refreshes the
+	// panel, freeing CPU at the cost of some flicker; 0 means unlimited. This
+	// is unrelated to how often a new frame is sent with Render, the panel is
+	// refreshed continuously with the last frame either way.
+	LimitRefreshRateHz int
+
+	// Name of GPIO mapping used: "regular" (default), "regular-pi1",
+	// "adafruit-hat", "adafruit-hat-pwm", "classic", "classic-pi1" or
+	// "compute-module"
 	HardwareMapping string
+
+	// RGBSequence is the order of the color channels on the panel, any
+	// permutation of "RGB", such as "BGR" for panels with red and blue swapped.
+	RGBSequence string
+
+	// PanelType is the chipset of panels needing a specific initialization
+	// sequence before they light up, "FM6126A" or "FM6127". Empty for regular
+	// panels.
+	PanelType string
+
+	// PixelMapper is a semicolon separated list of pixel mappers applied to the
+	// matrix, in order, such as "U-mapper;Rotate:180". Supported mappers are
+	// "U-mapper", "V-mapper", "Rotate:<angle>" with the angle being a multiple
+	// of 90 and "Mirror:H" or "Mirror:V", the names are case insensitive.
+	// Mappers changing the aspect of the matrix are taken into account by
+	// Geometry.
+	PixelMapper string
+
+	// EmulatorScale and EmulatorPixelPitchToGutterRatio configure the window
+	// of the emulator returned by NewRGBLedMatrix when MATRIX_EMULATOR is set,
+	// see emulator.Emulator SetScale and SetPixelPitchToGutterRatio. 0 keeps
+	// the emulator defaults. Ignored by real matrices.
+	EmulatorScale                   int
+	EmulatorPixelPitchToGutterRatio int
 }
 
 func (c *HardwareConfig) geometry() (width, height int) {
-	return c.Cols * c.ChainLength, c.Rows * c.Parallel
+	width, height = c.Cols*c.ChainLength, c.Rows*c.Parallel
+
+	mappers, _ := c.pixelMappers()
+	for _, m := range mappers {
+		switch m.name {
+		case "U-mapper":
+			width, height = width/2, height*2
+		case "V-mapper":
+			width, height = width*c.Parallel/c.ChainLength, height*c.ChainLength/c.Parallel
+		case "Rotate":
+			if angle, _ := strconv.Atoi(m.param); angle%180 != 0 {
+				width, height = height, width
+			}
+		}
+	}
+
+	return width, height
+}
+
+func (c *HardwareConfig) validate() error {
+	if c.ChainLength < 1 {
+		return fmt.Errorf("invalid chain length %d, must be 1 or greater", c.ChainLength)
+	}
+
+	if c.Parallel < 1 {
+		return fmt.Errorf("invalid parallel %d, must be 1 or greater", c.Parallel)
+	}
+
+	if c.PWMBits < 0 || c.PWMBits > 11 {
+		return fmt.Errorf("invalid PWM bits %d, valid range is 1..11, or 0 for the library default", c.PWMBits)
+	}
+
+	if c.PWMLSBNanoseconds < 0 {
+		return fmt.Errorf("invalid PWM LSB nanoseconds %d, must be positive", c.PWMLSBNanoseconds)
+	}
+
+	if c.GPIOSlowdown < 0 || c.GPIOSlowdown > 4 {
+		return fmt.Errorf("invalid GPIO slowdown %d, valid range is 1..4, or 0 for the library default", c.GPIOSlowdown)
+	}
+
+	if c.RowAddressType < 0 {
+		return fmt.Errorf("invalid row address type %d, must be positive", c.RowAddressType)
+	}
+
+	if c.Multiplexing < 0 {
+		return fmt.Errorf("invalid multiplexing %d, must be positive", c.Multiplexing)
+	}
+
+	if c.EmulatorScale < 0 {
+		return fmt.Errorf("invalid emulator scale %d, must be 1 or greater, or 0 for the default", c.EmulatorScale)
+	}
+
+	if c.EmulatorPixelPitchToGutterRatio < 0 {
+		return fmt.Errorf("invalid emulator pixel pitch to gutter ratio %d, must be 1 or greater, or 0 for the default", c.EmulatorPixelPitchToGutterRatio)
+	}
+
+	if c.LimitRefreshRateHz < 0 {
+		return fmt.Errorf("invalid refresh rate limit %d, must be 0 or greater", c.LimitRefreshRateHz)
+	}
+
+	if c.RGBSequence != "" && !isRGBPermutation(c.RGBSequence) {
+		return fmt.Errorf("invalid RGB sequence %q, must be a permutation of RGB", c.RGBSequence)
+	}
+
+	switch c.HardwareMapping {
+	case "", "regular", "regular-pi1", "adafruit-hat", "adafruit-hat-pwm",
+		"classic", "classic-pi1", "compute-module":
+	default:
+		return fmt.Errorf("unknown hardware mapping %q", c.HardwareMapping)
+	}
+
+	switch c.PanelType {
+	case "", "FM6126A", "FM6127":
+	default:
+		return fmt.Errorf("unknown panel type %q", c.PanelType)
+	}
+
+	if _, err := c.pixelMappers(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func isRGBPermutation(s string) bool {
+	s = strings.ToUpper(s)
+	return len(s) == 3 &&
+		strings.Count(s, "R") == 1 &&
+		strings.Count(s, "G") == 1 &&
+		strings.Count(s, "B") == 1
+}
+
+type pixelMapper struct {
+	name  string
+	param string
+}
+
+var pixelMapperNames = []string{"U-mapper", "V-mapper", "Rotate", "Mirror"}
+
+func (c *HardwareConfig) pixelMappers() ([]pixelMapper, error) {
+	var mappers []pixelMapper
+	for _, spec := range strings.Split(c.PixelMapper, ";") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		m := pixelMapper{name: spec}
+		if i := strings.Index(spec, ":"); i != -1 {
+			m.name, m.param = spec[:i], spec[i+1:]
+		}
+
+		// the library matches the names ignoring the case
+		for _, name := range pixelMapperNames {
+			if strings.EqualFold(m.name, name) {
+				m.name = name
+			}
+		}
+
+		switch m.name {
+		case "U-mapper":
+			if c.ChainLength%2 != 0 {
+				return nil, fmt.Errorf("invalid pixel mapper %q, requires an even chain length", spec)
+			}
+		case "V-mapper":
+		case "Rotate":
+			angle, err := strconv.Atoi(m.param)
+			if err != nil || angle%90 != 0 {
+				return nil, fmt.Errorf("invalid pixel mapper %q, angle must be a multiple of 90", spec)
+			}
+		case "Mirror":
+			m.param = strings.ToUpper(m.param)
+			if m.param != "H" && m.param != "V" {
+				return nil, fmt.Errorf("invalid pixel mapper %q, direction must be H or V", spec)
+			}
+		default:
+			return nil, fmt.Errorf("unknown pixel mapper %q", m.name)
+		}
+
+		mappers = append(mappers, m)
+	}
+
+	return mappers, nil
 }
 
 func (c *HardwareConfig) toC() *C.struct_RGBLedMatrixOptions {
@@ -113,7 +317,24 @@ func (c *HardwareConfig) toC() *C.struct_RGBLedMatrixOptions {
 	o.pwm_lsb_nanoseconds = C.int(c.PWMLSBNanoseconds)
 	o.brightness = C.int(c.Brightness)
 	o.scan_mode = C.int(c.ScanMode)
-	o.hardware_mapping = C.CString(c.HardwareMapping)
+	o.row_address_type = C.int(c.RowAddressType)
+	o.multiplexing = C.int(c.Multiplexing)
+	o.limit_refresh_rate_hz = C.int(c.LimitRefreshRateHz)
+	if c.HardwareMapping != "" {
+		o.hardware_mapping = C.CString(c.HardwareMapping)
+	}
+
+	if c.PixelMapper != "" {
+		o.pixel_mapper_config = C.CString(c.PixelMapper)
+	}
+
+	if c.RGBSequence != "" {
+		o.led_rgb_sequence = C.CString(c.RGBSequence)
+	}
+
+	if c.PanelType != "" {
+		o.panel_type = C.CString(c.PanelType)
+	}
 
 	if c.ShowRefreshRate == true {
 		C.set_show_refresh_rate(o, C.int(1))
@@ -136,6 +357,13 @@ func (c *HardwareConfig) toC() *C.struct_RGBLedMatrixOptions {
 	return o
 }
 
+func (c *HardwareConfig) toRuntimeC() *C.struct_RGBLedRuntimeOptions {
+	o := &C.struct_RGBLedRuntimeOptions{}
+	o.gpio_slowdown = C.int(c.GPIOSlowdown)
+
+	return o
+}
+
 type ScanMode int8
 
 const (
@@ -168,12 +396,16 @@ func NewRGBLedMatrix(config *HardwareConfig) (c Matrix, err error) {
 		}
 	}()
 
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
 	if isMatrixEmulator() {
-		return buildMatrixEmulator(config), nil
+		return buildMatrixEmulator(config)
 	}
 
 	w, h := config.geometry()
-	m := C.led_matrix_create_from_options(config.toC(), nil, nil)
+	m := C.led_matrix_create_from_options_and_rt_options(config.toC(), config.toRuntimeC())
 	b := C.led_matrix_create_offscreen_canvas(m)
 	c = &RGBLedMatrix{
 		Config: config,
@@ -197,9 +429,25 @@ func isMatrixEmulator() bool {
 	return false
 }
 
-func buildMatrixEmulator(config *HardwareConfig) Matrix {
+func buildMatrixEmulator(config *HardwareConfig) (Matrix, error) {
 	w, h := config.geometry()
-	return emulator.NewEmulator(w, h, emulator.DefaultPixelPitch, true)
+	e := emulator.NewEmulator(w, h, emulator.DefaultPixelPitch, false)
+	e.Brightness = config.Brightness
+
+	if config.EmulatorPixelPitchToGutterRatio != 0 {
+		if err := e.SetPixelPitchToGutterRatio(config.EmulatorPixelPitchToGutterRatio); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.EmulatorScale != 0 {
+		if err := e.SetScale(config.EmulatorScale); err != nil {
+			return nil, err
+		}
+	}
+
+	e.Init()
+	return e, nil
 }
 
 // Initialize initialize library, must be called once before other functions are
@@ -222,18 +470,23 @@ func (c *RGBLedMatrix) Apply(leds []color.Color) error {
 	return c.Render()
 }
 
+// ErrMatrixClosed is returned by the methods of a RGBLedMatrix after Close
+var ErrMatrixClosed = errors.New("matrix is closed")
+
 // Render update the display with the data from the LED buffer
 func (c *RGBLedMatrix) Render() error {
-	w, h := c.Config.geometry()
+	if c.matrix == nil {
+		return ErrMatrixClosed
+	}
 
-	C.led_matrix_swap(
+	c.buffer = C.led_matrix_swap(
 		c.matrix,
 		c.buffer,
-		C.int(w), C.int(h),
+		C.int(c.width), C.int(c.height),
 		(*C.uint32_t)(unsafe.Pointer(&c.leds[0])),
 	)
 
-	c.leds = make([]C.uint32_t, w*h)
+	c.leds = make([]C.uint32_t, c.width*c.height)
 	return nil
 }
 
@@ -248,9 +501,34 @@ func (c *RGBLedMatrix) Set(position int, color color.Color) {
 	c.leds[position] = C.uint32_t(colorToUint32(color))
 }
 
-// Close finalizes the ws281x interface
+// SetBrightness changes the brightness of the panel in percent, valid range is
+// 1..100. 0 is rejected since the library doesn't support a brightness of 0,
+// to turn the panel off for the night use Canvas.SetBrightnessScale(0)
+func (c *RGBLedMatrix) SetBrightness(brightness int) error {
+	if brightness < 1 || brightness > 100 {
+		return fmt.Errorf("invalid brightness %d, valid range is 1..100", brightness)
+	}
+
+	if c.matrix == nil {
+		return ErrMatrixClosed
+	}
+
+	C.led_matrix_set_brightness(c.matrix, C.uint8_t(brightness))
+	c.Config.Brightness = brightness
+	return nil
+}
+
+// Close finalizes the ws281x interface, the panel is cleared and the GPIO
+// released. Calling Close more than once has no effect, Render and
+// SetBrightness return ErrMatrixClosed afterwards.
 func (c *RGBLedMatrix) Close() error {
+	if c.matrix == nil {
+		return nil
+	}
+
 	C.led_matrix_delete(c.matrix)
+	c.matrix = nil
+	c.buffer = nil
 	return nil
 }
 
@@ -0,0 +1,254 @@
+package rgbmatrix
+
+import (
+	"unsafe"
+
+	. "gopkg.in/check.v1"
+)
+
+type MatrixSuite struct{}
+
+var _ = Suite(&MatrixSuite{})
+
+func (s *MatrixSuite) TestGeometryChain(c *C) {
+	config := DefaultConfig
+	config.Rows = 64
+	config.Cols = 64
+	config.ChainLength = 3
+	config.Parallel = 1
+
+	w, h := config.geometry()
+	c.Assert(w, Equals, 192)
+	c.Assert(h, Equals, 64)
+}
+
+func (s *MatrixSuite) TestGeometryParallel(c *C) {
+	config := DefaultConfig
+	config.Rows = 32
+	config.Cols = 64
+	config.ChainLength = 2
+	config.Parallel = 3
+
+	w, h := config.geometry()
+	c.Assert(w, Equals, 128)
+	c.Assert(h, Equals, 96)
+}
+
+func (s *MatrixSuite) TestGeometryPixelMapperRotate(c *C) {
+	config := DefaultConfig
+	config.Rows = 32
+	config.Cols = 64
+	config.ChainLength = 1
+	config.Parallel = 1
+	config.PixelMapper = "Rotate:90"
+	c.Assert(config.validate(), IsNil)
+
+	w, h := config.geometry()
+	c.Assert(w, Equals, 32)
+	c.Assert(h, Equals, 64)
+
+	config.PixelMapper = "Rotate:180"
+	w, h = config.geometry()
+	c.Assert(w, Equals, 64)
+	c.Assert(h, Equals, 32)
+}
+
+func (s *MatrixSuite) TestGeometryPixelMapperUMapper(c *C) {
+	config := DefaultConfig
+	config.Rows = 32
+	config.Cols = 64
+	config.ChainLength = 2
+	config.Parallel = 1
+	config.PixelMapper = "U-mapper;Mirror:H"
+	c.Assert(config.validate(), IsNil)
+
+	w, h := config.geometry()
+	c.Assert(w, Equals, 64)
+	c.Assert(h, Equals, 64)
+}
+
+func (s *MatrixSuite) TestGeometryPixelMapperCase(c *C) {
+	config := DefaultConfig
+	config.Rows = 32
+	config.Cols = 64
+	config.ChainLength = 2
+	config.Parallel = 1
+	config.PixelMapper = "u-mapper;rotate:90;mirror:h"
+	c.Assert(config.validate(), IsNil)
+
+	w, h := config.geometry()
+	c.Assert(w, Equals, 64)
+	c.Assert(h, Equals, 64)
+
+	config.PixelMapper = "ROTATE:90"
+	w, h = config.geometry()
+	c.Assert(w, Equals, 32)
+	c.Assert(h, Equals, 128)
+}
+
+func (s *MatrixSuite) TestValidatePixelMapper(c *C) {
+	config := DefaultConfig
+	config.ChainLength = 1
+
+	config.PixelMapper = "Foo"
+	c.Assert(config.validate(), ErrorMatches, `unknown pixel mapper "Foo"`)
+
+	config.PixelMapper = "Rotate:45"
+	c.Assert(config.validate(), ErrorMatches, `invalid pixel mapper "Rotate:45", .*`)
+
+	config.PixelMapper = "Mirror"
+	c.Assert(config.validate(), ErrorMatches, `invalid pixel mapper "Mirror", .*`)
+
+	config.PixelMapper = "U-mapper"
+	c.Assert(config.validate(), ErrorMatches, `invalid pixel mapper "U-mapper", .*`)
+}
+
+func (s *MatrixSuite) TestLimitRefreshRateHz(c *C) {
+	config := DefaultConfig
+	c.Assert(int(config.toC().limit_refresh_rate_hz), Equals, 0)
+
+	config.LimitRefreshRateHz = 120
+	c.Assert(config.validate(), IsNil)
+	c.Assert(int(config.toC().limit_refresh_rate_hz), Equals, 120)
+
+	config.LimitRefreshRateHz = -1
+	c.Assert(config.validate(), NotNil)
+}
+
+func (s *MatrixSuite) TestDefaultConfig(c *C) {
+	c.Assert(DefaultConfig.PWMBits, Equals, 11)
+	c.Assert(DefaultConfig.PWMLSBNanoseconds, Equals, 130)
+	c.Assert(DefaultConfig.GPIOSlowdown, Equals, 1)
+	c.Assert(DefaultConfig.validate(), IsNil)
+}
+
+func (s *MatrixSuite) TestSetBrightnessInvalid(c *C) {
+	m := &RGBLedMatrix{}
+	for _, b := range []int{-1, 0, 101} {
+		c.Assert(m.SetBrightness(b), ErrorMatches, "invalid brightness .*, valid range is 1..100")
+	}
+}
+
+func (s *MatrixSuite) TestClosed(c *C) {
+	m := &RGBLedMatrix{Config: &DefaultConfig}
+	c.Assert(m.Close(), IsNil)
+	c.Assert(m.Render(), Equals, ErrMatrixClosed)
+	c.Assert(m.SetBrightness(50), Equals, ErrMatrixClosed)
+}
+
+func (s *MatrixSuite) TestValidateChainAndParallel(c *C) {
+	config := DefaultConfig
+	config.PixelMapper = "V-mapper"
+	config.ChainLength = 0
+	c.Assert(config.validate(), ErrorMatches, "invalid chain length 0, must be 1 or greater")
+
+	config.ChainLength = 1
+	config.Parallel = 0
+	c.Assert(config.validate(), ErrorMatches, "invalid parallel 0, must be 1 or greater")
+}
+
+func (s *MatrixSuite) TestValidateEmulator(c *C) {
+	config := DefaultConfig
+	config.EmulatorScale = 2
+	config.EmulatorPixelPitchToGutterRatio = 4
+	c.Assert(config.validate(), IsNil)
+
+	config.EmulatorScale = -1
+	c.Assert(config.validate(), ErrorMatches, "invalid emulator scale -1, .*")
+
+	config.EmulatorScale = 0
+	config.EmulatorPixelPitchToGutterRatio = -1
+	c.Assert(config.validate(), ErrorMatches, "invalid emulator pixel pitch to gutter ratio -1, .*")
+}
+
+func (s *MatrixSuite) TestGPIOSlowdown(c *C) {
+	config := DefaultConfig
+	config.GPIOSlowdown = 4
+	c.Assert(config.validate(), IsNil)
+	c.Assert(int(config.toRuntimeC().gpio_slowdown), Equals, 4)
+
+	config.GPIOSlowdown = 5
+	c.Assert(config.validate(), ErrorMatches, "invalid GPIO slowdown 5, valid range is 1..4, or 0 for the library default")
+}
+
+func (s *MatrixSuite) TestValidatePWM(c *C) {
+	config := DefaultConfig
+	config.PWMBits = 0
+	c.Assert(config.validate(), IsNil)
+
+	config.PWMBits = 12
+	c.Assert(config.validate(), ErrorMatches, "invalid PWM bits 12, valid range is 1..11, or 0 for the library default")
+
+	config.PWMBits = -1
+	c.Assert(config.validate(), ErrorMatches, "invalid PWM bits -1, .*")
+
+	config.PWMBits = 1
+	config.PWMLSBNanoseconds = -1
+	c.Assert(config.validate(), ErrorMatches, "invalid PWM LSB nanoseconds -1, .*")
+}
+
+func (s *MatrixSuite) TestPanelType(c *C) {
+	config := DefaultConfig
+	c.Assert(config.toC().panel_type, IsNil)
+
+	config.PanelType = "FM6126A"
+	c.Assert(config.validate(), IsNil)
+	c.Assert(goString(unsafe.Pointer(config.toC().panel_type)), Equals, "FM6126A")
+
+	config.PanelType = "FM6128"
+	c.Assert(config.validate(), ErrorMatches, `unknown panel type "FM6128"`)
+}
+
+func (s *MatrixSuite) TestRGBSequence(c *C) {
+	config := DefaultConfig
+	c.Assert(config.RGBSequence, Equals, "RGB")
+
+	config.RGBSequence = "BGR"
+	c.Assert(config.validate(), IsNil)
+	c.Assert(goString(unsafe.Pointer(config.toC().led_rgb_sequence)), Equals, "BGR")
+
+	config.RGBSequence = "RG"
+	c.Assert(config.validate(), ErrorMatches, `invalid RGB sequence "RG", .*`)
+
+	config.RGBSequence = "RRB"
+	c.Assert(config.validate(), ErrorMatches, `invalid RGB sequence "RRB", .*`)
+}
+
+func (s *MatrixSuite) TestRowAddressTypeAndMultiplexing(c *C) {
+	config := DefaultConfig
+	c.Assert(config.RowAddressType, Equals, 0)
+	c.Assert(config.Multiplexing, Equals, 0)
+	c.Assert(int(config.toC().row_address_type), Equals, 0)
+	c.Assert(int(config.toC().multiplexing), Equals, 0)
+
+	config.RowAddressType = 1
+	config.Multiplexing = 3
+	c.Assert(config.validate(), IsNil)
+	c.Assert(int(config.toC().row_address_type), Equals, 1)
+	c.Assert(int(config.toC().multiplexing), Equals, 3)
+}
+
+func (s *MatrixSuite) TestHardwareMapping(c *C) {
+	config := DefaultConfig
+	c.Assert(config.HardwareMapping, Equals, "regular")
+	c.Assert(goString(unsafe.Pointer(config.toC().hardware_mapping)), Equals, "regular")
+
+	config.HardwareMapping = "adafruit-hat-pwm"
+	c.Assert(config.validate(), IsNil)
+	c.Assert(goString(unsafe.Pointer(config.toC().hardware_mapping)), Equals, "adafruit-hat-pwm")
+
+	config.HardwareMapping = "adafruit"
+	c.Assert(config.validate(), ErrorMatches, `unknown hardware mapping "adafruit"`)
+}
+
+// goString reads a NUL terminated C string, cgo can't be used on tests
+func goString(p unsafe.Pointer) string {
+	b := (*[1 << 10]byte)(p)
+
+	var n int
+	for b[n] != 0 {
+		n++
+	}
+
+	return string(b[:n])
+}
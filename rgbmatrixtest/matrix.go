@@ -0,0 +1,141 @@
+// Package rgbmatrixtest provides an in-memory rgbmatrix.Matrix, useful to test
+// code drawing to a matrix without a real or emulated panel.
+package rgbmatrixtest
+
+import (
+	"image"
+	"image/color"
+	"sync"
+
+	"github.com/mcuadros/go-rpi-rgb-led-matrix"
+)
+
+var _ rgbmatrix.Matrix = &Matrix{}
+
+// Matrix is a rgbmatrix.Matrix keeping the LEDs in memory, it records the
+// calls made to it and the last rendered frame
+type Matrix struct {
+	w, h       int
+	leds       []color.Color
+	frame      *image.RGBA
+	calls      map[string]int
+	brightness int
+
+	mu sync.Mutex
+}
+
+// NewMatrix returns a new Matrix with the given geometry
+func NewMatrix(w, h int) *Matrix {
+	return &Matrix{
+		w:          w,
+		h:          h,
+		leds:       make([]color.Color, w*h),
+		frame:      image.NewRGBA(image.Rect(0, 0, w, h)),
+		calls:      make(map[string]int, 0),
+		brightness: 100,
+	}
+}
+
+// Geometry returns the width and the height of the matrix
+func (m *Matrix) Geometry() (width, height int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls["Geometry"]++
+	return m.w, m.h
+}
+
+// At returns the color of the LED at position, not yet rendered
+func (m *Matrix) At(position int) color.Color {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls["At"]++
+	if m.leds[position] == nil {
+		return color.Black
+	}
+
+	return m.leds[position]
+}
+
+// Set set LED at position to the provided color
+func (m *Matrix) Set(position int, c color.Color) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls["Set"]++
+	m.leds[position] = color.RGBAModel.Convert(c)
+}
+
+// Apply set all the pixels to the values contained in leds and renders them
+func (m *Matrix) Apply(leds []color.Color) error {
+	for position, l := range leds {
+		m.Set(position, l)
+	}
+
+	return m.Render()
+}
+
+// Render copies the LEDs to the frame returned by Frame, and resets them as a
+// real matrix does
+func (m *Matrix) Render() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls["Render"]++
+	for position, c := range m.leds {
+		if c == nil {
+			c = color.Black
+		}
+
+		m.frame.Set(position%m.w, position/m.w, c)
+	}
+
+	m.leds = make([]color.Color, m.w*m.h)
+	return nil
+}
+
+// SetBrightness records the given brightness, it can be read with Brightness
+func (m *Matrix) SetBrightness(brightness int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls["SetBrightness"]++
+	m.brightness = brightness
+	return nil
+}
+
+// Close records the call, the matrix is still usable after it
+func (m *Matrix) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls["Close"]++
+	return nil
+}
+
+// Frame returns a copy of the last rendered frame
+func (m *Matrix) Frame() image.Image {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	frame := image.NewRGBA(m.frame.Bounds())
+	copy(frame.Pix, m.frame.Pix)
+	return frame
+}
+
+// Brightness returns the last value passed to SetBrightness, 100 by default
+func (m *Matrix) Brightness() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.brightness
+}
+
+// Calls returns how many times the method with the given name was called
+func (m *Matrix) Calls(method string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.calls[method]
+}
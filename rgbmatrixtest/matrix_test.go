@@ -0,0 +1,62 @@
+package rgbmatrixtest
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/mcuadros/go-rpi-rgb-led-matrix"
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type MatrixSuite struct{}
+
+var _ = Suite(&MatrixSuite{})
+
+func (s *MatrixSuite) TestGeometry(c *C) {
+	w, h := NewMatrix(64, 32).Geometry()
+	c.Assert(w, Equals, 64)
+	c.Assert(h, Equals, 32)
+}
+
+func (s *MatrixSuite) TestRender(c *C) {
+	m := NewMatrix(4, 2)
+	m.Set(5, color.White)
+
+	c.Assert(m.At(5), Equals, color.Color(color.RGBA{255, 255, 255, 255}))
+	c.Assert(m.Frame().At(1, 1), Equals, color.RGBA{})
+
+	err := m.Render()
+	c.Assert(err, IsNil)
+	c.Assert(m.Calls("Render"), Equals, 1)
+	c.Assert(m.At(5), Equals, color.Black)
+	c.Assert(m.Frame().At(1, 1), Equals, color.RGBA{255, 255, 255, 255})
+	c.Assert(m.Frame().At(0, 0), Equals, color.RGBA{0, 0, 0, 255})
+}
+
+func (s *MatrixSuite) TestCanvas(c *C) {
+	m := NewMatrix(4, 2)
+	canvas := rgbmatrix.NewCanvas(m)
+
+	red := color.RGBA{255, 0, 0, 255}
+	draw.Draw(canvas, canvas.Bounds(), &image.Uniform{red}, image.ZP, draw.Src)
+	err := canvas.Render()
+	c.Assert(err, IsNil)
+
+	frame := m.Frame()
+	c.Assert(frame.Bounds(), Equals, image.Rect(0, 0, 4, 2))
+	c.Assert(frame.At(0, 0), Equals, red)
+	c.Assert(frame.At(3, 1), Equals, red)
+}
+
+func (s *MatrixSuite) TestSetBrightness(c *C) {
+	m := NewMatrix(4, 2)
+	c.Assert(m.Brightness(), Equals, 100)
+
+	err := m.SetBrightness(40)
+	c.Assert(err, IsNil)
+	c.Assert(m.Brightness(), Equals, 40)
+}
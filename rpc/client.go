@@ -73,6 +73,12 @@ func (m *Client) Set(position int, c color.Color) {
 	m.leds[position] = color.RGBAModel.Convert(c)
 }
 
+// SetBrightness changes the brightness of the remote matrix in percent
+func (c *Client) SetBrightness(brightness int) error {
+	var reply *SetBrightnessReply
+	return c.client.Call("RPCMatrix.SetBrightness", &SetBrightnessArgs{Brightness: brightness}, &reply)
+}
+
 // Close finalizes the ws281x interface
 func (c *Client) Close() error {
 	return c.Apply(make([]color.Color, 2048))
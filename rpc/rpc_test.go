@@ -0,0 +1,38 @@
+package rpc
+
+import (
+	"net"
+	"net/rpc"
+	"testing"
+
+	"github.com/mcuadros/go-rpi-rgb-led-matrix/rgbmatrixtest"
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type RPCSuite struct{}
+
+var _ = Suite(&RPCSuite{})
+
+// newPipeClient returns a Client connected through a pipe to a server
+// exposing m
+func newPipeClient(c *C, m *rgbmatrixtest.Matrix) *Client {
+	server := rpc.NewServer()
+	c.Assert(server.Register(&RPCMatrix{m}), IsNil)
+
+	conn, serverConn := net.Pipe()
+	go server.ServeConn(serverConn)
+
+	return &Client{client: rpc.NewClient(conn)}
+}
+
+func (s *RPCSuite) TestSetBrightness(c *C) {
+	m := rgbmatrixtest.NewMatrix(4, 2)
+	client := newPipeClient(c, m)
+	defer client.client.Close()
+
+	c.Assert(client.SetBrightness(40), IsNil)
+	c.Assert(m.Brightness(), Equals, 40)
+	c.Assert(m.Calls("SetBrightness"), Equals, 1)
+}
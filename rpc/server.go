@@ -33,6 +33,13 @@ func (m *RPCMatrix) Apply(args *ApplyArgs, reply *ApplyReply) error {
 	return m.m.Apply(args.Colors)
 }
 
+type SetBrightnessArgs struct{ Brightness int }
+type SetBrightnessReply struct{}
+
+func (m *RPCMatrix) SetBrightness(args *SetBrightnessArgs, reply *SetBrightnessReply) error {
+	return m.m.SetBrightness(args.Brightness)
+}
+
 type CloseArgs struct{}
 type CloseReply struct{}
 
@@ -1,11 +1,15 @@
 package rgbmatrix
 
 import (
+	"context"
+	"fmt"
 	"image"
 	"image/draw"
 	"image/gif"
 	"io"
 	"time"
+
+	xdraw "golang.org/x/image/draw"
 )
 
 // ToolKit is a convinient set of function to operate with a led of Matrix
@@ -21,6 +25,12 @@ type ToolKit struct {
 	//		return imaging.Fill(img, 64, 96, imaging.Center, imaging.Lanczos)
 	//	}
 	Transform func(img image.Image) *image.NRGBA
+
+	// DitherBits if between 1 and 7, the images are reduced to the given
+	// number of bits per color using Floyd-Steinberg dithering before being
+	// drawn, reducing the banding of photographs on panels with a low color
+	// depth. It's expensive in CPU, so is disabled by default.
+	DitherBits int
 }
 
 // NewToolKit returns a new ToolKit wrapping the given Matrix
@@ -30,17 +40,16 @@ func NewToolKit(m Matrix) *ToolKit {
 	}
 }
 
-// PlayImage draws the given image during the given delay
-func (tk *ToolKit) PlayImage(i image.Image, delay time.Duration) error {
-	start := time.Now()
-	defer func() { time.Sleep(delay - time.Since(start)) }()
-
-	if tk.Transform != nil {
-		i = tk.Transform(i)
-	}
+// PlayImage draws the given image during the given delay or until ctx is
+// canceled, in which case the error of ctx is returned. Images with a size
+// different to the canvas are scaled to fit it and centered
+func (tk *ToolKit) PlayImage(ctx context.Context, i image.Image, delay time.Duration) error {
+	// the timer starts before drawing, so the time spent drawing the image is
+	// part of the delay
+	t := time.NewTimer(delay)
+	defer t.Stop()
 
-	draw.Draw(tk.Canvas, tk.Canvas.Bounds(), i, image.ZP, draw.Over)
-	return tk.Canvas.Render()
+	return tk.PlayImageUntil(ctx, i, t.C)
 }
 
 type Animation interface {
@@ -48,8 +57,9 @@ type Animation interface {
 }
 
 // PlayAnimation play the image during the delay returned by Next, until an err
-// is returned, if io.EOF is returned, PlayAnimation finish without an error
-func (tk *ToolKit) PlayAnimation(a Animation) error {
+// is returned or ctx is canceled, if io.EOF is returned, PlayAnimation finish
+// without an error. When canceled the error of ctx is returned.
+func (tk *ToolKit) PlayAnimation(ctx context.Context, a Animation) error {
 	var err error
 	var i image.Image
 	var n <-chan time.Time
@@ -60,7 +70,7 @@ func (tk *ToolKit) PlayAnimation(a Animation) error {
 			break
 		}
 
-		if err := tk.PlayImageUntil(i, n); err != nil {
+		if err := tk.PlayImageUntil(ctx, i, n); err != nil {
 			return err
 		}
 	}
@@ -72,68 +82,219 @@ func (tk *ToolKit) PlayAnimation(a Animation) error {
 	return err
 }
 
-// PlayImageUntil draws the given image until is notified to stop
-func (tk *ToolKit) PlayImageUntil(i image.Image, notify <-chan time.Time) error {
-	defer func() {
-		<-notify
-	}()
+// PlayImageUntil draws the given image until is notified to stop or ctx is
+// canceled, in which case the image stays on the matrix and the error of ctx
+// is returned
+func (tk *ToolKit) PlayImageUntil(ctx context.Context, i image.Image, notify <-chan time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
+	tk.draw(i)
+	if err := tk.Canvas.Render(); err != nil {
+		return err
+	}
+
+	select {
+	case <-notify:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// draw applies the Transform function and draws the image on the canvas,
+// dithered if DitherBits is set
+func (tk *ToolKit) draw(i image.Image) {
 	if tk.Transform != nil {
 		i = tk.Transform(i)
 	}
 
-	draw.Draw(tk.Canvas, tk.Canvas.Bounds(), i, image.ZP, draw.Over)
-	return tk.Canvas.Render()
+	if tk.DitherBits < 1 || tk.DitherBits > 7 {
+		compose(tk.Canvas, i)
+		return
+	}
+
+	bounds := tk.Canvas.Bounds()
+	img := image.NewRGBA(bounds)
+	draw.Draw(img, bounds, tk.Canvas, bounds.Min, draw.Src)
+	compose(img, i)
+	dither(img, uint(tk.DitherBits))
+	draw.Draw(tk.Canvas, bounds, img, bounds.Min, draw.Src)
 }
 
-// PlayImages draws a sequence of images during the given delays, the len of
-// images should be equal to the len of delay. If loop is true the function
-// loops over images until a true is sent to the returned chan
-func (tk *ToolKit) PlayImages(images []image.Image, delay []time.Duration, loop int) chan bool {
-	quit := make(chan bool, 0)
-
-	go func() {
-		l := len(images)
-		i := 0
-		for {
-			select {
-			case <-quit:
-				return
-			default:
-				tk.PlayImage(images[i], delay[i])
+// compose draws i over dst, if the size of the image doesn't match dst the
+// image is scaled keeping its aspect ratio, centered, and the remaining area
+// is filled with black
+func compose(dst draw.Image, i image.Image) {
+	bounds := dst.Bounds()
+	size := i.Bounds().Size()
+	if size == bounds.Size() || size.X == 0 || size.Y == 0 {
+		draw.Draw(dst, bounds, i, i.Bounds().Min, draw.Over)
+		return
+	}
+
+	draw.Draw(dst, bounds, image.Black, image.ZP, draw.Src)
+	xdraw.ApproxBiLinear.Scale(dst, fit(size, bounds), i, i.Bounds(), draw.Over, nil)
+}
+
+// dither reduces the colors of img to the given bits per channel, diffusing
+// the quantization error of each pixel to its neighbours following the
+// Floyd-Steinberg weights: 7/16 right, 3/16 below left, 5/16 below and 1/16
+// below right
+func dither(img *image.RGBA, bits uint) {
+	levels := 1<<bits - 1
+	b := img.Bounds()
+
+	// errors, in 16ths, carried to the current and the next row for each
+	// channel, with a margin of one pixel at both sides
+	stride := (b.Dx() + 2) * 3
+	cur, next := make([]int, stride), make([]int, stride)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := 0; x < b.Dx(); x++ {
+			o := img.PixOffset(b.Min.X+x, y)
+			for ch := 0; ch < 3; ch++ {
+				e := (x+1)*3 + ch
+				v := int(img.Pix[o+ch]) + cur[e]/16
+				q := quantize(v, levels)
+				img.Pix[o+ch] = uint8(q)
+
+				d := v - q
+				cur[e+3] += d * 7
+				next[e-3] += d * 3
+				next[e] += d * 5
+				next[e+3] += d
 			}
+		}
+
+		cur, next = next, cur
+		for i := range next {
+			next[i] = 0
+		}
+	}
+}
+
+// quantize returns the closest value to v, clamped to 0-255, of the given
+// number of levels evenly distributed between 0 and 255
+func quantize(v, levels int) int {
+	if v < 0 {
+		v = 0
+	} else if v > 255 {
+		v = 255
+	}
 
-			i++
-			if i >= l {
-				if loop == 0 {
-					i = 0
-					continue
-				}
+	return (v*levels + 127) / 255 * 255 / levels
+}
+
+// fit returns the largest rectangle with the aspect ratio of size centered
+// inside of r
+func fit(size image.Point, r image.Rectangle) image.Rectangle {
+	w, h := r.Dx(), size.Y*r.Dx()/size.X
+	if h > r.Dy() {
+		w, h = size.X*r.Dy()/size.Y, r.Dy()
+	}
+
+	min := r.Min.Add(image.Pt((r.Dx()-w)/2, (r.Dy()-h)/2))
+	return image.Rectangle{Min: min, Max: min.Add(image.Pt(w, h))}
+}
+
+// PlayImages draws a sequence of images during the given delays, the len of
+// images should be equal to the len of delay. The sequence is played the given
+// number of loops, or forever if loops is 0, until ctx is canceled. When
+// canceled the error of ctx is returned.
+func (tk *ToolKit) PlayImages(ctx context.Context, images []image.Image, delay []time.Duration, loops int) error {
+	if len(images) != len(delay) {
+		return fmt.Errorf("invalid delays, got %d delays for %d images", len(delay), len(images))
+	}
+
+	if len(images) == 0 {
+		return fmt.Errorf("invalid images, at least one image is required")
+	}
+
+	if loops < 0 {
+		return fmt.Errorf("invalid loops %d, must be 0 or greater", loops)
+	}
+
+	for loop := 0; loops == 0 || loop < loops; loop++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
-				break
+		for i := range images {
+			if err := tk.PlayImage(ctx, images[i], delay[i]); err != nil {
+				return err
 			}
 		}
-	}()
+	}
 
-	return quit
+	return nil
 }
 
 // PlayGIF reads and draw a gif file from r. It use the contained images and
-// delays and loops over it, until a true is sent to the returned chan
-func (tk *ToolKit) PlayGIF(r io.Reader) (chan bool, error) {
-	gif, err := gif.DecodeAll(r)
+// delays and loops over it as many times as the GIF defines, forever for most
+// of the animated GIFs, until ctx is canceled. When canceled the error of ctx
+// is returned.
+func (tk *ToolKit) PlayGIF(ctx context.Context, r io.Reader) error {
+	g, err := gif.DecodeAll(r)
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	// LoopCount is 0 when looping forever, -1 when played only once and
+	// otherwise the number of times the GIF is repeated after the first one
+	var loops int
+	switch {
+	case g.LoopCount < 0:
+		loops = 1
+	case g.LoopCount > 0:
+		loops = g.LoopCount + 1
 	}
 
-	delay := make([]time.Duration, len(gif.Delay))
-	images := make([]image.Image, len(gif.Image))
-	for i, image := range gif.Image {
-		images[i] = image
-		delay[i] = time.Millisecond * time.Duration(gif.Delay[i]) * 10
+	images, delay := composeGIF(g)
+	return tk.PlayImages(ctx, images, delay, loops)
+}
+
+// composeGIF returns the frames of g as full size opaque images, since the
+// frames of a GIF may cover only part of the image, and its delays. Each frame
+// is drawn over the previous ones following their disposal method, the
+// background being black.
+func composeGIF(g *gif.GIF) ([]image.Image, []time.Duration) {
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	canvas := image.NewRGBA(bounds)
+	draw.Draw(canvas, bounds, image.Black, image.ZP, draw.Src)
+
+	delay := make([]time.Duration, len(g.Image))
+	images := make([]image.Image, len(g.Image))
+	for i, frame := range g.Image {
+		var disposal byte
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+
+		var previous *image.RGBA
+		if disposal == gif.DisposalPrevious {
+			previous = cloneRGBA(canvas)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		images[i] = cloneRGBA(canvas)
+		delay[i] = time.Millisecond * time.Duration(g.Delay[i]) * 10
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Black, image.ZP, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
 	}
 
-	return tk.PlayImages(images, delay, gif.LoopCount), nil
+	return images, delay
+}
+
+func cloneRGBA(img *image.RGBA) *image.RGBA {
+	clone := image.NewRGBA(img.Bounds())
+	copy(clone.Pix, img.Pix)
+	return clone
 }
 
 // Close close the toolkit and the inner canvas
@@ -0,0 +1,446 @@
+package rgbmatrix
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type ToolKitSuite struct{}
+
+var _ = Suite(&ToolKitSuite{})
+
+var (
+	red   = color.RGBA{255, 0, 0, 255}
+	blue  = color.RGBA{0, 0, 255, 255}
+	black = color.RGBA{0, 0, 0, 255}
+)
+
+func (s *ToolKitSuite) TestPlayImage(c *C) {
+	m := NewMatrixMockWithGeometry(4, 2)
+	tk := NewToolKit(m)
+
+	err := tk.PlayImage(context.Background(), solid(4, 2, red), 0)
+	c.Assert(err, IsNil)
+	c.Assert(m.called["Render"], Equals, true)
+	assertPixels(c, tk.Canvas, []string{
+		"RRRR",
+		"RRRR",
+	})
+}
+
+func (s *ToolKitSuite) TestPlayImageScaleUp(c *C) {
+	m := NewMatrixMockWithGeometry(8, 4)
+	tk := NewToolKit(m)
+
+	err := tk.PlayImage(context.Background(), solid(2, 2, red), 0)
+	c.Assert(err, IsNil)
+	assertPixels(c, tk.Canvas, []string{
+		"..RRRR..",
+		"..RRRR..",
+		"..RRRR..",
+		"..RRRR..",
+	})
+}
+
+func (s *ToolKitSuite) TestPlayImageScaleDown(c *C) {
+	m := NewMatrixMockWithGeometry(4, 4)
+	tk := NewToolKit(m)
+
+	err := tk.PlayImage(context.Background(), solid(16, 8, red), 0)
+	c.Assert(err, IsNil)
+	assertPixels(c, tk.Canvas, []string{
+		"....",
+		"RRRR",
+		"RRRR",
+		"....",
+	})
+}
+
+func (s *ToolKitSuite) TestPlayImageTransparent(c *C) {
+	m := NewMatrixMockWithGeometry(4, 2)
+	tk := NewToolKit(m)
+
+	a := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	a.Set(0, 0, red)
+	b := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	b.Set(1, 0, red)
+
+	c.Assert(tk.PlayImage(context.Background(), a, 0), IsNil)
+	c.Assert(tk.PlayImage(context.Background(), b, 0), IsNil)
+
+	// the second frame is drawn over black, not over the first one
+	c.Assert(color.RGBAModel.Convert(m.colors[0]), Equals, color.RGBA{})
+	assertPixels(c, tk.Canvas, []string{
+		".R..",
+		"....",
+	})
+}
+
+func (s *ToolKitSuite) TestPlayImages(c *C) {
+	m := NewMatrixMockWithGeometry(4, 2)
+	tk := NewToolKit(m)
+
+	images := []image.Image{solid(4, 2, red), solid(4, 2, blue), solid(4, 2, black)}
+	delay := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond}
+
+	start := time.Now()
+	err := tk.PlayImages(context.Background(), images, delay, 1)
+	c.Assert(err, IsNil)
+	c.Assert(time.Since(start) >= 60*time.Millisecond, Equals, true)
+
+	c.Assert(m.renders, HasLen, 3)
+	c.Assert(color.RGBAModel.Convert(m.renders[0].color), Equals, red)
+	c.Assert(color.RGBAModel.Convert(m.renders[1].color), Equals, blue)
+	c.Assert(color.RGBAModel.Convert(m.renders[2].color), Equals, black)
+}
+
+func (s *ToolKitSuite) TestPlayImagesLoops(c *C) {
+	m := NewMatrixMockWithGeometry(4, 2)
+	tk := NewToolKit(m)
+
+	images := []image.Image{solid(4, 2, red), solid(4, 2, blue)}
+	err := tk.PlayImages(context.Background(), images, make([]time.Duration, 2), 3)
+	c.Assert(err, IsNil)
+	c.Assert(m.renders, HasLen, 6)
+}
+
+func (s *ToolKitSuite) TestPlayImagesInvalidDelays(c *C) {
+	m := NewMatrixMockWithGeometry(4, 2)
+	tk := NewToolKit(m)
+
+	images := []image.Image{solid(4, 2, red), solid(4, 2, blue)}
+	err := tk.PlayImages(context.Background(), images, make([]time.Duration, 1), 1)
+	c.Assert(err, ErrorMatches, "invalid delays, got 1 delays for 2 images")
+	c.Assert(m.renders, HasLen, 0)
+
+	err = tk.PlayImages(context.Background(), images, make([]time.Duration, 2), -1)
+	c.Assert(err, ErrorMatches, "invalid loops -1, must be 0 or greater")
+	c.Assert(m.renders, HasLen, 0)
+}
+
+func (s *ToolKitSuite) TestPlayImagesEmpty(c *C) {
+	m := NewMatrixMockWithGeometry(4, 2)
+	tk := NewToolKit(m)
+
+	err := tk.PlayImages(context.Background(), nil, nil, 0)
+	c.Assert(err, ErrorMatches, "invalid images, at least one image is required")
+	c.Assert(m.renders, HasLen, 0)
+}
+
+func (s *ToolKitSuite) TestPlayImageCanceled(c *C) {
+	m := NewMatrixMockWithGeometry(4, 2)
+	tk := NewToolKit(m)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := tk.PlayImage(ctx, solid(4, 2, red), 30*time.Second)
+	c.Assert(err, Equals, context.Canceled)
+	c.Assert(time.Since(start) < 500*time.Millisecond, Equals, true)
+	c.Assert(m.renders, HasLen, 1)
+}
+
+func (s *ToolKitSuite) TestPlayImageUntilCanceled(c *C) {
+	m := NewMatrixMockWithGeometry(4, 2)
+	tk := NewToolKit(m)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	err := tk.PlayImageUntil(ctx, solid(4, 2, red), nil)
+	c.Assert(err, Equals, context.Canceled)
+	c.Assert(m.renders, HasLen, 1)
+}
+
+func (s *ToolKitSuite) TestPlayImagesCanceled(c *C) {
+	m := NewMatrixMockWithGeometry(4, 2)
+	tk := NewToolKit(m)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	images := []image.Image{solid(4, 2, red), solid(4, 2, blue)}
+	delay := []time.Duration{time.Second, time.Second}
+
+	start := time.Now()
+	err := tk.PlayImages(ctx, images, delay, 0)
+	c.Assert(err, Equals, context.Canceled)
+	c.Assert(time.Since(start) < 500*time.Millisecond, Equals, true)
+
+	c.Assert(m.renders, HasLen, 1)
+	assertPixels(c, tk.Canvas, []string{
+		"RRRR",
+		"RRRR",
+	})
+}
+
+func (s *ToolKitSuite) TestPlayImagesCanceledBefore(c *C) {
+	m := NewMatrixMockWithGeometry(4, 2)
+	tk := NewToolKit(m)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	images := []image.Image{solid(4, 2, red)}
+	err := tk.PlayImages(ctx, images, make([]time.Duration, 1), 0)
+	c.Assert(err, Equals, context.Canceled)
+	c.Assert(m.renders, HasLen, 0)
+}
+
+func (s *ToolKitSuite) TestPlayAnimationCanceled(c *C) {
+	m := NewMatrixMockWithGeometry(4, 2)
+	tk := NewToolKit(m)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := tk.PlayAnimation(ctx, &AnimationMock{img: solid(4, 2, red)})
+	c.Assert(err, Equals, context.Canceled)
+	c.Assert(time.Since(start) < 500*time.Millisecond, Equals, true)
+	c.Assert(m.renders, HasLen, 1)
+}
+
+func (s *ToolKitSuite) TestPlayAnimationEOF(c *C) {
+	m := NewMatrixMockWithGeometry(4, 2)
+	tk := NewToolKit(m)
+
+	err := tk.PlayAnimation(context.Background(), &AnimationMock{img: solid(4, 2, red), frames: 3})
+	c.Assert(err, IsNil)
+	c.Assert(m.renders, HasLen, 3)
+}
+
+func (s *ToolKitSuite) TestPlayGIF(c *C) {
+	m := NewMatrixMockWithGeometry(4, 2)
+	tk := NewToolKit(m)
+
+	g := &gif.GIF{
+		Image:     []*image.Paletted{paletted(image.Rect(0, 0, 4, 2), 1), paletted(image.Rect(0, 0, 4, 2), 2)},
+		Delay:     []int{2, 4},
+		LoopCount: -1,
+	}
+
+	buf := bytes.NewBuffer(nil)
+	err := gif.EncodeAll(buf, g)
+	c.Assert(err, IsNil)
+
+	start := time.Now()
+	err = tk.PlayGIF(context.Background(), buf)
+	c.Assert(err, IsNil)
+	c.Assert(time.Since(start) >= 60*time.Millisecond, Equals, true)
+
+	c.Assert(m.renders, HasLen, 2)
+	c.Assert(color.RGBAModel.Convert(m.renders[0].color), Equals, red)
+	c.Assert(color.RGBAModel.Convert(m.renders[1].color), Equals, blue)
+	c.Assert(m.renders[1].at.Sub(m.renders[0].at) >= 20*time.Millisecond, Equals, true)
+}
+
+func (s *ToolKitSuite) TestPlayGIFCanceled(c *C) {
+	m := NewMatrixMockWithGeometry(4, 2)
+	tk := NewToolKit(m)
+
+	g := &gif.GIF{
+		Image: []*image.Paletted{paletted(image.Rect(0, 0, 4, 2), 1)},
+		Delay: []int{0},
+	}
+
+	buf := bytes.NewBuffer(nil)
+	err := gif.EncodeAll(buf, g)
+	c.Assert(err, IsNil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = tk.PlayGIF(ctx, buf)
+	c.Assert(err, Equals, context.Canceled)
+}
+
+func (s *ToolKitSuite) TestComposeGIFDisposal(c *C) {
+	full := image.Rect(0, 0, 4, 2)
+	g := &gif.GIF{
+		Image: []*image.Paletted{
+			paletted(full, 1),
+			paletted(image.Rect(0, 0, 2, 2), 2),
+			paletted(image.Rect(2, 0, 4, 2), 2),
+			paletted(image.Rect(0, 0, 1, 1), 1),
+		},
+		Delay:    []int{1, 2, 3, 4},
+		Disposal: []byte{gif.DisposalNone, gif.DisposalBackground, gif.DisposalPrevious, gif.DisposalNone},
+		Config:   image.Config{Width: 4, Height: 2},
+	}
+
+	images, delay := composeGIF(g)
+	c.Assert(images, HasLen, 4)
+	c.Assert(delay, DeepEquals, []time.Duration{
+		10 * time.Millisecond, 20 * time.Millisecond,
+		30 * time.Millisecond, 40 * time.Millisecond,
+	})
+
+	c.Assert(images[1].At(0, 0), Equals, blue)
+	c.Assert(images[1].At(3, 0), Equals, red)
+
+	c.Assert(images[2].At(0, 0), Equals, black)
+	c.Assert(images[2].At(3, 0), Equals, blue)
+
+	c.Assert(images[3].At(0, 0), Equals, red)
+	c.Assert(images[3].At(1, 0), Equals, black)
+	c.Assert(images[3].At(3, 0), Equals, red)
+}
+
+func (s *ToolKitSuite) TestFit(c *C) {
+	r := image.Rect(0, 0, 64, 32)
+	c.Assert(fit(image.Pt(64, 32), r), Equals, r)
+	c.Assert(fit(image.Pt(10, 10), r), Equals, image.Rect(16, 0, 48, 32))
+	c.Assert(fit(image.Pt(128, 32), r), Equals, image.Rect(0, 8, 64, 24))
+}
+
+func (s *ToolKitSuite) TestDither(c *C) {
+	m := NewMatrixMockWithGeometry(64, 32)
+	tk := NewToolKit(m)
+	tk.DitherBits = 1
+
+	gray := color.RGBA{128, 128, 128, 255}
+	c.Assert(tk.PlayImage(context.Background(), solid(64, 32, gray), 0), IsNil)
+
+	// the error of the first pixel, rounded to white, makes black the pixel
+	// at its right and the one below
+	white := color.RGBA{255, 255, 255, 255}
+	blk := color.RGBA{0, 0, 0, 255}
+	c.Assert(tk.Canvas.last().At(0, 0), Equals, white)
+	c.Assert(tk.Canvas.last().At(1, 0), Equals, blk)
+	c.Assert(tk.Canvas.last().At(2, 0), Equals, white)
+	c.Assert(tk.Canvas.last().At(0, 1), Equals, blk)
+}
+
+func (s *ToolKitSuite) TestDitherGradient(c *C) {
+	m := NewMatrixMockWithGeometry(64, 32)
+	tk := NewToolKit(m)
+	tk.DitherBits = 2
+
+	img := image.NewRGBA(image.Rect(0, 0, 64, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 64; x++ {
+			v := uint8(x * 255 / 63)
+			img.Set(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+
+	c.Assert(tk.PlayImage(context.Background(), img, 0), IsNil)
+
+	// every pixel takes one of the 4 levels of 2 bits, the error being spread
+	// to the neighbours keeps the average intensity of each block of 4 columns
+	// close to the gradient
+	for x := 0; x < 64; x += 4 {
+		var sum, expected int
+		for dx := 0; dx < 4; dx++ {
+			expected += (x + dx) * 255 / 63 * 32
+			for y := 0; y < 32; y++ {
+				px := tk.Canvas.last().At(x+dx, y).(color.RGBA)
+				c.Assert(px.R%85, Equals, uint8(0), Commentf("pixel %d,%d", x+dx, y))
+				sum += int(px.R)
+			}
+		}
+
+		diff := (sum - expected) / 128
+		c.Assert(diff > -8 && diff < 8, Equals, true, Commentf("columns %d-%d", x, x+3))
+	}
+}
+
+func (s *ToolKitSuite) TestDitherDisabled(c *C) {
+	m := NewMatrixMockWithGeometry(64, 32)
+	tk := NewToolKit(m)
+
+	gray := color.RGBA{128, 128, 128, 255}
+	c.Assert(tk.PlayImage(context.Background(), solid(64, 32, gray), 0), IsNil)
+	c.Assert(tk.Canvas.last().At(1, 0), Equals, gray)
+}
+
+func solid(w, h int, c color.Color) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+
+	return img
+}
+
+// AnimationMock returns the same image every frame, during a second, stopping
+// after the given number of frames if not zero
+type AnimationMock struct {
+	img    image.Image
+	frames int
+	played int
+}
+
+func (a *AnimationMock) Next() (image.Image, <-chan time.Time, error) {
+	if a.frames != 0 && a.played == a.frames {
+		return nil, nil, io.EOF
+	}
+
+	a.played++
+	if a.frames != 0 {
+		return a.img, time.After(0), nil
+	}
+
+	return a.img, time.After(time.Second), nil
+}
+
+// paletted returns a frame filled with the color at index i of the palette
+func paletted(r image.Rectangle, i uint8) *image.Paletted {
+	img := image.NewPaletted(r, color.Palette{black, red, blue})
+	for j := range img.Pix {
+		img.Pix[j] = i
+	}
+
+	return img
+}
+
+// assertPixels checks the canvas against rows of R for red and . for black
+func assertPixels(c *C, canvas *Canvas, rows []string) {
+	for y, row := range rows {
+		for x, px := range row {
+			expected := black
+			if px == 'R' {
+				expected = red
+			}
+
+			c.Assert(canvas.last().At(x, y), Equals, expected, Commentf("pixel %d,%d", x, y))
+		}
+	}
+}
+
+// BenchmarkPlayImage and BenchmarkPlayImageScaled measure a full frame, from
+// the image to Render, with and without scaling. On amd64 with nopMatrix they
+// went from ~135µs and ~173µs to ~78µs and ~150µs per frame.
+func BenchmarkPlayImage(b *testing.B) {
+	tk := NewToolKit(&nopMatrix{64, 32})
+	img := solid(64, 32, red)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tk.PlayImage(context.Background(), img, 0)
+	}
+}
+
+func BenchmarkPlayImageScaled(b *testing.B) {
+	tk := NewToolKit(&nopMatrix{64, 32})
+	img := solid(128, 128, red)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tk.PlayImage(context.Background(), img, 0)
+	}
+}